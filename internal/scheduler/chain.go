@@ -4,11 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/cybertec-postgresql/pg_timetable/internal/log"
 	"github.com/cybertec-postgresql/pg_timetable/internal/pgengine"
+	"github.com/cybertec-postgresql/pg_timetable/internal/scheduler/pool"
 	pgx "github.com/jackc/pgx/v4"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // Chain structure used to represent tasks chains
@@ -19,6 +22,14 @@ type Chain struct {
 	SelfDestruct       bool   `db:"self_destruct"`
 	ExclusiveExecution bool   `db:"exclusive_execution"`
 	MaxInstances       int    `db:"max_instances"`
+	RetryMaxAttempts   int    `db:"retry_max_attempts"`
+	RetryBackoffBase   int    `db:"retry_backoff_base_ms"`
+	RetryBackoffMax    int    `db:"retry_backoff_max_ms"`
+	RetryOnExitCodes   []int  `db:"retry_on_exit_codes"`
+	RetryExcludeCodes  []int  `db:"retry_exclude_exit_codes"`
+	Priority           int    `db:"priority"`
+	MaxParallel        int    `db:"max_parallel"`
+	attempt            int    // in-memory retry attempt counter; not persisted
 }
 
 func (chain Chain) String() string {
@@ -50,6 +61,10 @@ func (sch *Scheduler) retrieveAsyncChainsAndRun(ctx context.Context) {
 		if chainSignal.ConfigID == 0 {
 			return
 		}
+		if !sch.leader.IsLeader() {
+			sch.l.Debug("Not the leader, ignoring chain signal")
+			continue
+		}
 		switch chainSignal.Command {
 		case "START":
 			var headChain Chain
@@ -58,12 +73,12 @@ func (sch *Scheduler) retrieveAsyncChainsAndRun(ctx context.Context) {
 				sch.l.WithError(err).Error("Could not query pending tasks")
 			} else {
 				sch.l.WithField("chain", headChain.ChainID).
-					Debug("Putting head chain to the execution channel")
-				sch.chainsChan <- headChain
+					Debug("Submitting head chain to the worker pool")
+				sch.submitChain(ctx, headChain)
 			}
 		case "STOP":
-			if cancel, ok := sch.activeChains[chainSignal.ConfigID]; ok {
-				cancel()
+			if active, ok := sch.activeChains[chainSignal.ConfigID]; ok {
+				active.cancel()
 			}
 		}
 	}
@@ -71,6 +86,10 @@ func (sch *Scheduler) retrieveAsyncChainsAndRun(ctx context.Context) {
 
 func (sch *Scheduler) retrieveChainsAndRun(ctx context.Context, reboot bool) {
 	var err error
+	if !sch.leader.IsLeader() {
+		sch.l.Debug("Not the leader, skipping this retrieval cycle")
+		return
+	}
 	msg := "Retrieve scheduled chains to run"
 	if reboot {
 		msg = msg + " @reboot"
@@ -94,14 +113,23 @@ func (sch *Scheduler) retrieveChainsAndRun(ctx context.Context, reboot bool) {
 			time.Sleep(time.Duration(refetchTimeout*1000/headChainsCount) * time.Millisecond)
 		}
 		sch.l.WithField("chain", headChain.ChainID).
-			Debug("Putting head chain to the execution channel")
-		sch.chainsChan <- headChain
+			Debug("Submitting head chain to the worker pool")
+		sch.submitChain(ctx, headChain)
+	}
+}
+
+// submitChain hands a chain to the worker pool, logging an error if the
+// pool rejects it (the pool is draining during shutdown, or ctx ends
+// while Submit is blocked on a full queue under RejectBlock).
+func (sch *Scheduler) submitChain(ctx context.Context, chain Chain) {
+	if err := sch.pool.Submit(ctx, chainTask{sch: sch, chain: chain}); err != nil {
+		sch.l.WithError(err).WithField("chain", chain.ChainID).Error("Could not submit chain to worker pool")
 	}
 }
 
 func (sch *Scheduler) addActiveChain(id int, cancel context.CancelFunc) {
 	sch.activeChainMutex.Lock()
-	sch.activeChains[id] = cancel
+	sch.activeChains[id] = activeChain{cancel: cancel, startedAt: time.Now()}
 	sch.activeChainMutex.Unlock()
 }
 
@@ -111,44 +139,78 @@ func (sch *Scheduler) deleteActiveChain(id int) {
 	sch.activeChainMutex.Unlock()
 }
 
-func (sch *Scheduler) chainWorker(ctx context.Context, chains <-chan Chain) {
-	for {
-		select {
-		case <-ctx.Done(): //check context with high priority
-			return
-		default:
-			select {
-			case chain := <-chains:
-				chainL := sch.l.WithField("chain", chain.ChainID)
-				chainContext := log.WithLogger(ctx, chainL)
-				chainL.Info("Starting chain")
-				if !sch.pgengine.CanProceedChainExecution(chainContext, chain.ChainID, chain.MaxInstances) {
-					chainL.Debug("Cannot proceed. Sleeping")
-					continue
-				}
-				sch.Lock(chain.ExclusiveExecution)
-				chainContext, cancel := context.WithCancel(chainContext)
-				sch.addActiveChain(chain.TaskID, cancel)
-				sch.executeChain(chainContext, chain.ChainID, chain.TaskID)
-				if chain.SelfDestruct {
-					sch.pgengine.DeleteChainConfig(chainContext, chain.ChainID)
-				}
-				sch.deleteActiveChain(chain.TaskID)
-				cancel()
-				sch.Unlock(chain.ExclusiveExecution)
-			case <-ctx.Done():
-				return
-			}
+// chainTask adapts a Chain into the pool.Task interface expected by the
+// worker pool, so chains sit in the pool's priority heap instead of the
+// former plain chainsChan.
+type chainTask struct {
+	sch   *Scheduler
+	chain Chain
+}
+
+// Priority orders chains within the pool's queue; higher values run
+// first. It is consulted by the pool whenever workers are free, so a
+// high-priority chain submitted later can still preempt lower-priority
+// chains still waiting in the queue.
+func (t chainTask) Priority() int { return t.chain.Priority }
 
+// Run executes a single chain instance. It is invoked by a pool worker
+// goroutine in place of the old fixed chainWorker loop.
+func (t chainTask) Run(ctx context.Context) {
+	sch, chain := t.sch, t.chain
+	chainL := sch.l.WithField("chain", chain.ChainID)
+	chainContext := log.WithLogger(ctx, chainL)
+	chainL.Info("Starting chain")
+	if !sch.pgengine.CanProceedChainExecution(chainContext, chain.ChainID, chain.MaxInstances) {
+		chainL.Debug("Cannot proceed. Sleeping")
+		return
+	}
+	sch.Lock(chain.ExclusiveExecution)
+	chainContext, cancel := context.WithCancel(chainContext)
+	sch.addActiveChain(chain.TaskID, cancel)
+	attempt := chain.attempt
+	if attempt == 0 {
+		attempt = 1
+	}
+	sch.executeChain(chainContext, chain.ChainID, chain.TaskID, attempt, chain.MaxParallel)
+	if chain.SelfDestruct {
+		sch.pgengine.DeleteChainConfig(chainContext, chain.ChainID)
+	}
+	sch.deleteActiveChain(chain.TaskID)
+	cancel()
+	sch.Unlock(chain.ExclusiveExecution)
+}
+
+// newChainPool builds the worker pool that backs submitChain, sized and
+// configured from the scheduler's resource settings. Its Prometheus
+// collectors are registered against the default registry here so
+// queue_size/active_workers/queue_wait_seconds are actually scraped, not
+// just updated in memory.
+func newChainPool(sch *Scheduler) *pool.Pool {
+	p := pool.New(pool.Options{
+		MinWorkers:   sch.Config().Resource.CronWorkers,
+		MaxWorkers:   sch.Config().Resource.CronWorkers,
+		IdleTimeout:  5 * time.Minute,
+		QueueSize:    sch.Config().Resource.CronWorkers * refetchTimeout,
+		RejectPolicy: pool.RejectBlock,
+	})
+	for _, c := range p.Collectors() {
+		if err := prometheus.Register(c); err != nil {
+			sch.l.WithError(err).Warn("Could not register worker pool metrics")
 		}
 	}
+	return p
 }
 
 /* execute a chain of tasks */
-func (sch *Scheduler) executeChain(ctx context.Context, chainID int, taskID int) {
+func (sch *Scheduler) executeChain(ctx context.Context, chainID int, taskID int, attempt int, maxParallel int) {
 	var ChainElements []pgengine.ChainElement
 	var bctx context.Context
-	chainL := sch.l.WithField("chain", chainID)
+	chainL := sch.l.WithField("chain", chainID).WithField("attempt", attempt)
+
+	// Captured once up front: if this process is deposed mid-execution, the
+	// fencing token it carries goes stale and MustCommitTransactionFenced
+	// below rejects the commit instead of letting a split-brain write land.
+	fencingToken := sch.leader.FencingToken()
 
 	tx, err := sch.pgengine.StartTransaction(ctx)
 	if err != nil {
@@ -163,24 +225,15 @@ func (sch *Scheduler) executeChain(ctx context.Context, chainID int, taskID int)
 
 	runStatusID := sch.pgengine.InsertChainRunStatus(ctx, chainID, taskID)
 
-	/* now we can loop through every element of the task chain */
-	for _, chainElem := range ChainElements {
-		chainElem.ChainID = chainID
-		l := chainL.WithField("task", chainElem.CommandID)
-		l.Info("Starting task")
-		ctx = log.WithLogger(ctx, l)
-		sch.pgengine.UpdateChainRunStatus(ctx, &chainElem, runStatusID, "STARTED")
-		retCode := sch.executeСhainElement(ctx, tx, &chainElem)
-
-		// we use background context here because current one (ctx) might be cancelled
-		bctx = log.WithLogger(context.Background(), l)
-		if retCode != 0 && !chainElem.IgnoreError {
-			chainL.Error("Chain failed")
-			sch.pgengine.UpdateChainRunStatus(bctx, &chainElem, runStatusID, "CHAIN_FAILED")
-			sch.pgengine.MustRollbackTransaction(bctx, tx)
-			return
-		}
-		sch.pgengine.UpdateChainRunStatus(bctx, &chainElem, runStatusID, "CHAIN_DONE")
+	failedElem, retCode, ok := sch.runChainDAG(ctx, tx, chainID, runStatusID, ChainElements, maxParallel)
+	if !ok {
+		bctx = log.WithLogger(context.Background(), chainL.WithField("task", failedElem.CommandID))
+		chainL.Error("Chain failed")
+		sch.pgengine.UpdateChainRunStatus(bctx, failedElem, runStatusID, "CHAIN_FAILED")
+		sch.events.Publish(Event{Type: EventChainFailed, ChainID: chainID, TaskID: failedElem.TaskID, ExitCode: retCode})
+		sch.pgengine.MustRollbackTransaction(bctx, tx)
+		sch.handleChainFailure(bctx, chainID, taskID, retCode, attempt)
+		return
 	}
 	chainL.Info("Chain executed successfully")
 	bctx = log.WithLogger(context.Background(), chainL)
@@ -188,27 +241,42 @@ func (sch *Scheduler) executeChain(ctx context.Context, chainID int, taskID int)
 		&pgengine.ChainElement{
 			TaskID:  taskID,
 			ChainID: chainID}, runStatusID, "CHAIN_DONE")
-	sch.pgengine.MustCommitTransaction(bctx, tx)
+	sch.events.Publish(Event{Type: EventChainDone, ChainID: chainID, TaskID: taskID})
+	sch.pgengine.MustCommitTransactionFenced(bctx, tx, fencingToken)
 }
 
-func (sch *Scheduler) executeСhainElement(ctx context.Context, tx pgx.Tx, chainElem *pgengine.ChainElement) int {
+// executeСhainElement runs a single task. txMu must guard every access to
+// tx: GetChainParamValues and the SQL-kind branch of ExecuteSQLTask both
+// read/write through it and pgx.Tx isn't safe for concurrent use, but the
+// PROGRAM/BUILTIN branches talk to an external process or in-process
+// function with no tx involved at all, so they intentionally run outside
+// the lock — that's what lets independent DAG branches actually overlap
+// instead of serializing behind whichever sibling is mid-task.
+// executeСhainElement returns the task's exit code along with its
+// stdout and stderr separately, so callers publishing Events don't have
+// to re-split a single joined blob.
+func (sch *Scheduler) executeСhainElement(ctx context.Context, txMu *sync.Mutex, tx pgx.Tx, chainElem *pgengine.ChainElement) (retCode int, stdout string, stderr string) {
 	var paramValues []string
 	var err error
 	var out string
-	var retCode int
 	l := log.GetLogger(ctx)
-	if !sch.pgengine.GetChainParamValues(ctx, tx, &paramValues, chainElem) {
-		return -1
+	txMu.Lock()
+	ok := sch.pgengine.GetChainParamValues(ctx, tx, &paramValues, chainElem)
+	txMu.Unlock()
+	if !ok {
+		return -1, "", ""
 	}
 
 	chainElem.StartedAt = time.Now()
 	switch chainElem.Kind {
 	case "SQL":
+		txMu.Lock()
 		out, err = sch.pgengine.ExecuteSQLTask(ctx, tx, chainElem, paramValues)
+		txMu.Unlock()
 	case "PROGRAM":
 		if sch.pgengine.NoProgramTasks {
 			l.Info("Program task execution skipped")
-			return -1
+			return -1, "", ""
 		}
 		retCode, out, err = sch.ExecuteProgramCommand(ctx, chainElem.Script, paramValues)
 	case "BUILTIN":
@@ -220,11 +288,11 @@ func (sch *Scheduler) executeСhainElement(ctx context.Context, tx pgx.Tx, chain
 		if retCode == 0 {
 			retCode = -1
 		}
-		out = strings.Join([]string{out, err.Error()}, "\n")
+		stderr = err.Error()
 		l.WithError(err).Error("Task execution failed")
 	} else {
 		l.Info("Task executed successfully")
 	}
-	sch.pgengine.LogChainElementExecution(context.Background(), chainElem, retCode, out)
-	return 0
+	sch.pgengine.LogChainElementExecution(context.Background(), chainElem, retCode, strings.Join([]string{out, stderr}, "\n"))
+	return retCode, out, stderr
 }
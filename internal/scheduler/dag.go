@@ -0,0 +1,242 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/cybertec-postgresql/pg_timetable/internal/log"
+	"github.com/cybertec-postgresql/pg_timetable/internal/pgengine"
+	pgx "github.com/jackc/pgx/v4"
+)
+
+// dagNode tracks one ChainElement's place in the dependency graph: the
+// element itself, the element IDs it depends on, and a channel that's
+// closed once it finishes so dependents (and fan-in joins) can wait on it.
+type dagNode struct {
+	elem     pgengine.ChainElement
+	waitsOn  []int
+	done     chan struct{}
+	retCode  int
+	finished bool
+}
+
+// buildDAG indexes elements by ElementID and validates the dependency
+// graph: every dependency must refer to another element in the same
+// chain, and the graph must be acyclic. Without this, runChainDAG's
+// goroutines would wait on each other's done channels forever — a
+// misconfigured chain must fail fast here instead of deadlocking with an
+// open transaction.
+func buildDAG(elements []pgengine.ChainElement) (map[int]*dagNode, error) {
+	nodes := make(map[int]*dagNode, len(elements))
+	for _, elem := range elements {
+		nodes[elem.ElementID] = &dagNode{elem: elem, waitsOn: elem.Dependencies, done: make(chan struct{})}
+	}
+	for id, node := range nodes {
+		for _, dep := range node.waitsOn {
+			if _, ok := nodes[dep]; !ok {
+				return nil, fmt.Errorf("chain element %d depends on unknown element %d", id, dep)
+			}
+		}
+	}
+	if cycle := findCycle(nodes); cycle != nil {
+		return nil, fmt.Errorf("chain dependency cycle detected: %v", cycle)
+	}
+	return nodes, nil
+}
+
+// cycleState tags a node during the DFS walk in findCycle. The zero value
+// (unvisited) is what a fresh map returns for any node not yet touched,
+// visiting marks a node on the current recursion stack, and visited marks
+// a node that's been fully explored with no cycle found through it.
+type cycleState int
+
+const (
+	unvisited cycleState = iota
+	visiting
+	visited
+)
+
+// findCycle runs a DFS with an explicit recursion-stack check over the
+// dependency graph and returns the element IDs forming a cycle, or nil if
+// the graph is acyclic. A direct self-dependency (element depends on
+// itself) is just the degenerate one-node case of this same check.
+func findCycle(nodes map[int]*dagNode) []int {
+	state := make(map[int]cycleState, len(nodes))
+	var path []int
+	var visit func(id int) []int
+	visit = func(id int) []int {
+		state[id] = visiting
+		path = append(path, id)
+		for _, dep := range nodes[id].waitsOn {
+			switch state[dep] {
+			case visiting:
+				// found the back-edge; trim path down to where dep first appeared
+				for i, v := range path {
+					if v == dep {
+						return append(append([]int{}, path[i:]...), dep)
+					}
+				}
+			case visited:
+				continue
+			default: // unvisited
+				if cyc := visit(dep); cyc != nil {
+					return cyc
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[id] = visited
+		return nil
+	}
+	// Sort for deterministic error messages across runs with the same input.
+	ids := make([]int, 0, len(nodes))
+	for id := range nodes {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		if state[id] == unvisited {
+			if cyc := visit(id); cyc != nil {
+				return cyc
+			}
+		}
+	}
+	return nil
+}
+
+// waitForDepsThenAcquire blocks until every one of node's dependencies has
+// finished and a semaphore slot is free, then reports true once it holds
+// that slot. It reports false without acquiring a slot if branchCtx is
+// cancelled first — the signal a failed sibling branch uses to stop every
+// not-yet-started dependent from running at all.
+func waitForDepsThenAcquire(branchCtx context.Context, node *dagNode, nodes map[int]*dagNode, sem chan struct{}) bool {
+	for _, dep := range node.waitsOn {
+		select {
+		case <-nodes[dep].done:
+		case <-branchCtx.Done():
+			return false
+		}
+	}
+	select {
+	case <-branchCtx.Done():
+		return false
+	case sem <- struct{}{}:
+		// select picks at random among ready cases, so re-check after
+		// acquiring: a cancelled branch must not start even if its
+		// dependencies' done channels and a slot were ready too.
+		if branchCtx.Err() != nil {
+			<-sem
+			return false
+		}
+		return true
+	}
+}
+
+// runChainDAG executes a chain's elements honoring their depends_on
+// relationships: elements with no unmet dependencies run immediately,
+// later elements wait on their dependencies' done channels (the fan-in
+// point), and independent branches run concurrently bounded by a
+// semaphore sized maxParallel.
+//
+// Invariant: pgx.Tx is not safe for concurrent use, so every branch
+// shares the single transaction-scoped tx passed in, but all reads and
+// writes against it are serialized through txMu. This keeps the whole
+// chain inside one transaction (required for MustRollbackTransaction /
+// MustCommitTransactionFenced semantics) while still letting independent
+// task *processes* (PROGRAM tasks, external I/O) run in parallel; only the
+// brief moments spent talking to tx are serialized.
+//
+// On failure: a branch whose element isn't IgnoreError cancels the
+// branchCtx derived from ctx, which stops not-yet-started nodes from
+// being scheduled and lets already-running goroutines observe
+// branchCtx.Done() between steps. The first such failure is returned to
+// the caller; siblings that had already completed keep their CHAIN_DONE
+// status rows.
+func (sch *Scheduler) runChainDAG(
+	ctx context.Context, tx pgx.Tx, chainID int, runStatusID int,
+	elements []pgengine.ChainElement, maxParallel int,
+) (failedElem *pgengine.ChainElement, retCode int, ok bool) {
+	chainL := sch.l.WithField("chain", chainID)
+
+	nodes, err := buildDAG(elements)
+	if err != nil {
+		chainL.WithError(err).Error("Invalid chain dependency graph")
+		return &pgengine.ChainElement{ChainID: chainID, TaskID: -1}, -1, false
+	}
+	if maxParallel <= 0 {
+		maxParallel = 1
+	}
+
+	var (
+		txMu      sync.Mutex
+		failMu    sync.Mutex
+		firstFail *pgengine.ChainElement
+		firstCode int
+		wg        sync.WaitGroup
+		sem       = make(chan struct{}, maxParallel)
+	)
+	branchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var run func(id int)
+	run = func(id int) {
+		defer wg.Done()
+		node := nodes[id]
+		defer close(node.done)
+
+		if !waitForDepsThenAcquire(branchCtx, node, nodes, sem) {
+			return
+		}
+		defer func() { <-sem }()
+
+		elem := node.elem
+		elem.ChainID = chainID
+		l := chainL.WithField("task", elem.CommandID)
+		l.Info("Starting task")
+		elemCtx := log.WithLogger(branchCtx, l)
+
+		txMu.Lock()
+		sch.pgengine.UpdateChainRunStatus(elemCtx, &elem, runStatusID, "STARTED")
+		txMu.Unlock()
+		sch.events.Publish(Event{Type: EventChainStarted, ChainID: chainID, TaskID: elem.TaskID})
+
+		// txMu is passed through, not held here: executeСhainElement only
+		// takes it for the brief moments it actually touches tx, so a
+		// long-running PROGRAM task in one branch doesn't block its siblings.
+		var stdout, stderr string
+		node.retCode, stdout, stderr = sch.executeСhainElement(elemCtx, &txMu, tx, &elem)
+		node.finished = true
+		sch.events.Publish(Event{
+			Type: EventTaskFinished, ChainID: chainID, TaskID: elem.TaskID,
+			ExitCode: node.retCode, Stdout: stdout, Stderr: stderr, Duration: elem.Duration,
+		})
+
+		bctx := log.WithLogger(context.Background(), l)
+		if node.retCode != 0 && !elem.IgnoreError {
+			failMu.Lock()
+			if firstFail == nil {
+				firstFail = &elem
+				firstCode = node.retCode
+			}
+			failMu.Unlock()
+			cancel() // stop sibling branches and not-yet-started dependents
+			return
+		}
+		txMu.Lock()
+		sch.pgengine.UpdateChainRunStatus(bctx, &elem, runStatusID, "CHAIN_DONE")
+		txMu.Unlock()
+	}
+
+	wg.Add(len(nodes))
+	for id := range nodes {
+		go run(id)
+	}
+	wg.Wait()
+
+	if firstFail != nil {
+		return firstFail, firstCode, false
+	}
+	return nil, 0, true
+}
@@ -0,0 +1,140 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cybertec-postgresql/pg_timetable/internal/pgengine"
+)
+
+func elem(id int, deps ...int) pgengine.ChainElement {
+	return pgengine.ChainElement{ElementID: id, Dependencies: deps}
+}
+
+func TestBuildDAGAcceptsValidGraph(t *testing.T) {
+	elements := []pgengine.ChainElement{
+		elem(1),
+		elem(2, 1),
+		elem(3, 1),
+		elem(4, 2, 3), // fan-in on both branches
+	}
+	nodes, err := buildDAG(elements)
+	if err != nil {
+		t.Fatalf("unexpected error for a valid DAG: %v", err)
+	}
+	if len(nodes) != 4 {
+		t.Fatalf("expected 4 nodes, got %d", len(nodes))
+	}
+}
+
+func TestBuildDAGRejectsSelfDependency(t *testing.T) {
+	_, err := buildDAG([]pgengine.ChainElement{elem(1, 1)})
+	if err == nil {
+		t.Fatal("expected an error for a self-dependency, got nil")
+	}
+}
+
+func TestBuildDAGRejectsDanglingDependency(t *testing.T) {
+	_, err := buildDAG([]pgengine.ChainElement{elem(1, 99)})
+	if err == nil {
+		t.Fatal("expected an error for a dependency on a non-existent element, got nil")
+	}
+}
+
+func TestBuildDAGRejectsIndirectCycle(t *testing.T) {
+	// 1 -> 2 -> 3 -> 1: no self-loop anywhere, but still a cycle that
+	// would leave every goroutine in runChainDAG blocked on the others'
+	// done channel forever.
+	elements := []pgengine.ChainElement{
+		elem(1, 3),
+		elem(2, 1),
+		elem(3, 2),
+	}
+	_, err := buildDAG(elements)
+	if err == nil {
+		t.Fatal("expected an error for an indirect dependency cycle, got nil")
+	}
+}
+
+func TestWaitForDepsThenAcquireWaitsForAllDependencies(t *testing.T) {
+	nodes, err := buildDAG([]pgengine.ChainElement{elem(1), elem(2), elem(3, 1, 2)})
+	if err != nil {
+		t.Fatalf("unexpected error building DAG: %v", err)
+	}
+	sem := make(chan struct{}, 1)
+
+	acquired := make(chan bool, 1)
+	go func() { acquired <- waitForDepsThenAcquire(context.Background(), nodes[3], nodes, sem) }()
+
+	select {
+	case <-acquired:
+		t.Fatal("node 3 proceeded before either of its dependencies finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(nodes[1].done)
+	select {
+	case <-acquired:
+		t.Fatal("node 3 proceeded before its second dependency finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(nodes[2].done)
+	select {
+	case ok := <-acquired:
+		if !ok {
+			t.Fatal("expected waitForDepsThenAcquire to succeed once all dependencies are done")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for node 3 to proceed once its dependencies finished")
+	}
+}
+
+func TestWaitForDepsThenAcquireStopsOnCancelledBranch(t *testing.T) {
+	// Simulates a sibling branch failing: node 2 depends on node 1, which
+	// never finishes because the chain was cancelled out from under it —
+	// this is exactly what a failed sibling's cancel() call does to every
+	// not-yet-started dependent elsewhere in the DAG.
+	nodes, err := buildDAG([]pgengine.ChainElement{elem(1), elem(2, 1)})
+	if err != nil {
+		t.Fatalf("unexpected error building DAG: %v", err)
+	}
+	sem := make(chan struct{}, 1)
+	branchCtx, cancel := context.WithCancel(context.Background())
+
+	acquired := make(chan bool, 1)
+	go func() { acquired <- waitForDepsThenAcquire(branchCtx, nodes[2], nodes, sem) }()
+	cancel()
+
+	select {
+	case ok := <-acquired:
+		if ok {
+			t.Fatal("expected waitForDepsThenAcquire to report false once the branch context is cancelled")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for cancellation to stop the not-yet-started dependent")
+	}
+	// The semaphore slot must never have been taken, or a stuck node
+	// would leak it and starve every other branch sharing maxParallel.
+	select {
+	case sem <- struct{}{}:
+	default:
+		t.Fatal("semaphore slot was acquired despite the branch being cancelled")
+	}
+}
+
+func TestBuildDAGRejectsCycleNotReachableFromFirstNode(t *testing.T) {
+	// Element 1 has no dependencies and would be visited first; the cycle
+	// is entirely among 2 and 3, so the check must not stop after
+	// exploring from 1 alone.
+	elements := []pgengine.ChainElement{
+		elem(1),
+		elem(2, 3),
+		elem(3, 2),
+	}
+	_, err := buildDAG(elements)
+	if err == nil {
+		t.Fatal("expected an error for a cycle isolated from the root, got nil")
+	}
+}
@@ -0,0 +1,220 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cybertec-postgresql/pg_timetable/internal/pgengine"
+)
+
+// diagChainInstance describes a chain that is currently executing, as
+// tracked by activeChains.
+type diagChainInstance struct {
+	TaskID    int       `json:"task_id"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// diagQueueStats summarizes the worker pool queue depth and occupancy.
+type diagQueueStats struct {
+	QueueDepth   int `json:"queue_depth"`
+	ActiveChains int `json:"active_chains"`
+}
+
+// diagHistoryPage is a keyset page of past chain runs, ordered by
+// (started_at DESC, chain_id DESC) so callers can page backward without
+// an OFFSET scan.
+type diagHistoryPage struct {
+	Runs       []pgengineChainRun `json:"runs"`
+	NextBefore *diagCursor        `json:"next_before,omitempty"`
+}
+
+type diagCursor struct {
+	StartedAt time.Time `json:"started_at"`
+	ChainID   int       `json:"chain_id"`
+}
+
+// pgengineChainRun mirrors the row shape returned by
+// pgengine.SelectChainRunHistory.
+type pgengineChainRun struct {
+	ChainID   int       `json:"chain_id"`
+	ChainName string    `json:"chain_name"`
+	Status    string    `json:"status"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+const defaultHistoryPageSize = 50
+
+// DiagnosticsServer exposes read-only and operator-control endpoints over
+// the live state of a Scheduler: in-flight chain instances, worker pool
+// queue depth, and a keyset-paginated window into past chain runs.
+type DiagnosticsServer struct {
+	sch    *Scheduler
+	server *http.Server
+}
+
+// NewDiagnosticsServer builds a DiagnosticsServer bound to addr (the value
+// of the --diag-listen flag). The server is not started until Start is
+// called.
+func NewDiagnosticsServer(sch *Scheduler, addr string) *DiagnosticsServer {
+	d := &DiagnosticsServer{sch: sch}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/chains/live", d.handleLive)
+	mux.HandleFunc("/chains/history", d.handleHistory)
+	mux.HandleFunc("/chains/", d.handleChainAction)
+	d.server = &http.Server{Addr: addr, Handler: mux}
+	return d
+}
+
+// Start runs the diagnostics HTTP server until ctx is cancelled.
+func (d *DiagnosticsServer) Start(ctx context.Context) error {
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		d.server.Shutdown(shutdownCtx) //nolint: errcheck
+	}()
+	d.sch.l.WithField("addr", d.server.Addr).Info("Starting diagnostics HTTP server")
+	if err := d.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (d *DiagnosticsServer) handleLive(w http.ResponseWriter, r *http.Request) {
+	d.sch.activeChainMutex.Lock()
+	instances := make([]diagChainInstance, 0, len(d.sch.activeChains))
+	for taskID, active := range d.sch.activeChains {
+		instances = append(instances, diagChainInstance{TaskID: taskID, StartedAt: active.startedAt})
+	}
+	active := len(d.sch.activeChains)
+	d.sch.activeChainMutex.Unlock()
+
+	writeJSON(w, struct {
+		Instances    []diagChainInstance `json:"instances"`
+		Queue        diagQueueStats      `json:"queue"`
+		IsLeader     bool                `json:"is_leader"`
+		FencingToken int64               `json:"fencing_token"`
+	}{
+		Instances: instances,
+		Queue: diagQueueStats{
+			QueueDepth:   d.sch.pool.QueueLen(),
+			ActiveChains: active,
+		},
+		IsLeader:     d.sch.leader.IsLeader(),
+		FencingToken: d.sch.leader.FencingToken(),
+	})
+}
+
+// handleHistory serves a keyset-paginated page of past chain runs.
+// Query params: before_started_at (RFC3339), before_chain_id, chain_name,
+// status, limit.
+func (d *DiagnosticsServer) handleHistory(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit := defaultHistoryPageSize
+	if v := q.Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			limit = n
+		}
+	}
+	filter := pgengine.ChainRunHistoryFilter{
+		ChainName: q.Get("chain_name"),
+		Status:    q.Get("status"),
+		Limit:     limit,
+	}
+	if v := q.Get("before_started_at"); v != "" {
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			filter.BeforeStartedAt = t
+			filter.BeforeChainID, _ = strconv.Atoi(q.Get("before_chain_id"))
+		}
+	}
+	if v := q.Get("from"); v != "" {
+		filter.From, _ = time.Parse(time.RFC3339, v)
+	}
+	if v := q.Get("to"); v != "" {
+		filter.To, _ = time.Parse(time.RFC3339, v)
+	}
+
+	runs, err := d.sch.pgengine.SelectChainRunHistory(r.Context(), filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	page := diagHistoryPage{Runs: make([]pgengineChainRun, len(runs))}
+	for i, run := range runs {
+		page.Runs[i] = pgengineChainRun{
+			ChainID:   run.ChainID,
+			ChainName: run.ChainName,
+			Status:    run.Status,
+			StartedAt: run.StartedAt,
+		}
+	}
+	if len(runs) == limit {
+		last := runs[len(runs)-1]
+		page.NextBefore = &diagCursor{StartedAt: last.StartedAt, ChainID: last.ChainID}
+	}
+	writeJSON(w, page)
+}
+
+// handleChainAction dispatches POST /chains/{id}/cancel and
+// POST /chains/{id}/trigger.
+func (d *DiagnosticsServer) handleChainAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	path := r.URL.Path[len("/chains/"):]
+	var idStr, action string
+	for i := 0; i < len(path); i++ {
+		if path[i] == '/' {
+			idStr, action = path[:i], path[i+1:]
+			break
+		}
+	}
+	chainID, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "invalid chain id", http.StatusBadRequest)
+		return
+	}
+
+	switch action {
+	case "cancel":
+		d.cancelChain(w, chainID)
+	case "trigger":
+		d.triggerChain(w, r.Context(), chainID)
+	default:
+		http.Error(w, "unknown action", http.StatusNotFound)
+	}
+}
+
+func (d *DiagnosticsServer) cancelChain(w http.ResponseWriter, taskID int) {
+	d.sch.activeChainMutex.Lock()
+	active, ok := d.sch.activeChains[taskID]
+	d.sch.activeChainMutex.Unlock()
+	if !ok {
+		http.Error(w, "chain is not running", http.StatusNotFound)
+		return
+	}
+	active.cancel()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// triggerChain injects a synthetic START signal, equivalent to what
+// retrieveAsyncChainsAndRun does when it receives one from the database.
+func (d *DiagnosticsServer) triggerChain(w http.ResponseWriter, ctx context.Context, configID int) {
+	var headChain Chain
+	if err := d.sch.pgengine.SelectChain(ctx, &headChain, configID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	d.sch.submitChain(ctx, headChain)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v) //nolint: errcheck
+}
@@ -0,0 +1,116 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cybertec-postgresql/pg_timetable/internal/log"
+)
+
+// fakeLogger is a no-op log.LoggerIface for tests that need a Scheduler
+// but don't care about its log output.
+type fakeLogger struct{}
+
+func (l fakeLogger) WithField(string, interface{}) log.LoggerIface { return l }
+func (l fakeLogger) WithError(error) log.LoggerIface               { return l }
+func (l fakeLogger) Debug(...interface{})                          {}
+func (l fakeLogger) Info(...interface{})                           {}
+func (l fakeLogger) Warn(...interface{})                           {}
+func (l fakeLogger) Error(...interface{})                          {}
+
+func newTestScheduler() *Scheduler {
+	cfg := &Config{Resource: ResourceConfig{CronWorkers: 2}}
+	return NewScheduler(nil, cfg, fakeLogger{})
+}
+
+func TestHandleLiveReportsStartedAt(t *testing.T) {
+	sch := newTestScheduler()
+	before := time.Now()
+	sch.addActiveChain(42, func() {})
+	after := time.Now()
+
+	d := NewDiagnosticsServer(sch, "")
+	req := httptest.NewRequest(http.MethodGet, "/chains/live", nil)
+	w := httptest.NewRecorder()
+	d.handleLive(w, req)
+
+	var resp struct {
+		Instances []diagChainInstance `json:"instances"`
+		Queue     diagQueueStats      `json:"queue"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("could not unmarshal response: %v", err)
+	}
+	if len(resp.Instances) != 1 {
+		t.Fatalf("expected 1 live instance, got %d", len(resp.Instances))
+	}
+	got := resp.Instances[0]
+	if got.TaskID != 42 {
+		t.Errorf("expected task id 42, got %d", got.TaskID)
+	}
+	if got.StartedAt.Before(before) || got.StartedAt.After(after) {
+		t.Errorf("StartedAt %v not within [%v, %v]", got.StartedAt, before, after)
+	}
+	if resp.Queue.ActiveChains != 1 {
+		t.Errorf("expected 1 active chain in queue stats, got %d", resp.Queue.ActiveChains)
+	}
+}
+
+func TestCancelChainInvokesCancelFunc(t *testing.T) {
+	sch := newTestScheduler()
+	cancelled := make(chan struct{})
+	sch.addActiveChain(7, func() { close(cancelled) })
+
+	d := NewDiagnosticsServer(sch, "")
+	req := httptest.NewRequest(http.MethodPost, "/chains/7/cancel", nil)
+	w := httptest.NewRecorder()
+	d.handleChainAction(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d", http.StatusAccepted, w.Code)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("cancel endpoint did not invoke the chain's cancel func")
+	}
+}
+
+func TestCancelChainNotRunning(t *testing.T) {
+	sch := newTestScheduler()
+	d := NewDiagnosticsServer(sch, "")
+	req := httptest.NewRequest(http.MethodPost, "/chains/999/cancel", nil)
+	w := httptest.NewRecorder()
+	d.handleChainAction(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d for a chain that isn't running, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestHandleChainActionRejectsUnknownAction(t *testing.T) {
+	sch := newTestScheduler()
+	d := NewDiagnosticsServer(sch, "")
+	req := httptest.NewRequest(http.MethodPost, "/chains/1/pause", nil)
+	w := httptest.NewRecorder()
+	d.handleChainAction(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d for an unknown action, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestHandleChainActionRejectsNonPost(t *testing.T) {
+	sch := newTestScheduler()
+	d := NewDiagnosticsServer(sch, "")
+	req := httptest.NewRequest(http.MethodGet, "/chains/1/cancel", nil)
+	w := httptest.NewRecorder()
+	d.handleChainAction(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected status %d for a GET request, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+}
@@ -0,0 +1,191 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// EventType enumerates the chain and task lifecycle transitions that get
+// published to the configured EventSink. These mirror the statuses already
+// written by UpdateChainRunStatus and LogChainElementExecution.
+type EventType string
+
+// Event types published for every chain/task state transition.
+const (
+	EventChainStarted EventType = "STARTED"
+	EventChainFailed  EventType = "CHAIN_FAILED"
+	EventChainDone    EventType = "CHAIN_DONE"
+	EventTaskFinished EventType = "TASK_FINISHED"
+)
+
+// Event is the structured JSON message published for every chain/task
+// state transition, so downstream consumers can build dashboards or
+// trigger workflows without polling pgengine tables.
+type Event struct {
+	Type      EventType `json:"type"`
+	ChainID   int       `json:"chain_id"`
+	TaskID    int       `json:"task_id"`
+	ExitCode  int       `json:"exit_code,omitempty"`
+	Stdout    string    `json:"stdout,omitempty"`
+	Stderr    string    `json:"stderr,omitempty"`
+	Duration  int64     `json:"duration_us,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// EventSink publishes Events to an external stream (NATS JetStream,
+// Kafka, ...). Implementations must be safe for concurrent use.
+type EventSink interface {
+	Publish(ctx context.Context, event Event) error
+	Close() error
+}
+
+// NewEventSink builds the EventSink selected by the --event-sink flag.
+// The URL scheme picks the implementation: nats://host:port/subject or
+// kafka://broker1,broker2/topic.
+func NewEventSink(rawURL string) (EventSink, error) {
+	if rawURL == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --event-sink URL: %w", err)
+	}
+	switch u.Scheme {
+	case "nats":
+		return newNATSSink(u)
+	case "kafka":
+		return newKafkaSink(u)
+	default:
+		return nil, fmt.Errorf("unsupported event sink scheme %q", u.Scheme)
+	}
+}
+
+const eventBufferSize = 1024
+
+// EventBus buffers Events in a bounded channel and fans them out to the
+// configured sink. Publish failures don't block the caller: the event is
+// instead written to a durable outbox table that outboxDrainWorker drains
+// in the background, giving at-least-once delivery semantics.
+type EventBus struct {
+	sch       *Scheduler
+	sink      EventSink
+	buffer    chan Event
+	closeOnce sync.Once
+
+	// insertOutbox persists a failed-to-publish event; it's a field
+	// rather than a direct b.sch.pgengine.InsertEventOutbox call so tests
+	// can exercise the buffer-full/publish-failure fallback path without
+	// a live Postgres connection.
+	insertOutbox func(ctx context.Context, payload []byte) error
+}
+
+// NewEventBus wires sink (which may be nil, disabling publishing
+// entirely) into a bounded buffer drained by a background goroutine.
+func NewEventBus(sch *Scheduler, sink EventSink) *EventBus {
+	return &EventBus{
+		sch: sch, sink: sink,
+		buffer:       make(chan Event, eventBufferSize),
+		insertOutbox: sch.pgengine.InsertEventOutbox,
+	}
+}
+
+// Publish enqueues event for asynchronous delivery. It never blocks: if
+// the buffer is full the event is written directly to the outbox so
+// nothing is silently dropped.
+func (b *EventBus) Publish(event Event) {
+	if b == nil || b.sink == nil {
+		return
+	}
+	event.Timestamp = time.Now()
+	select {
+	case b.buffer <- event:
+	default:
+		b.sch.l.WithField("event", event.Type).Warn("Event buffer full, writing directly to outbox")
+		b.writeOutbox(context.Background(), event)
+	}
+}
+
+// Close shuts down the configured sink (the Kafka writer, the NATS
+// connection). It's safe to call more than once: Run and
+// outboxDrainWorker both defer it on their own exit, but only the first
+// caller actually closes the sink.
+func (b *EventBus) Close() error {
+	if b == nil || b.sink == nil {
+		return nil
+	}
+	var err error
+	b.closeOnce.Do(func() {
+		err = b.sink.Close()
+	})
+	return err
+}
+
+// Run drains the buffer, publishing each event to the sink. An event that
+// fails to publish is persisted to the outbox table instead of being
+// retried inline, so a slow or unavailable sink can't back up the chain
+// execution path.
+func (b *EventBus) Run(ctx context.Context) {
+	defer b.Close()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-b.buffer:
+			if err := b.sink.Publish(ctx, event); err != nil {
+				b.sch.l.WithError(err).Warn("Publishing event failed, falling back to outbox")
+				b.writeOutbox(ctx, event)
+			}
+		}
+	}
+}
+
+// writeOutbox persists an event that could not be published immediately
+// into timetable.event_outbox, for outboxDrainWorker to retry later.
+func (b *EventBus) writeOutbox(ctx context.Context, event Event) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		b.sch.l.WithError(err).Error("Could not marshal event for outbox")
+		return
+	}
+	if err := b.insertOutbox(ctx, payload); err != nil {
+		b.sch.l.WithError(err).Error("Could not persist event to outbox")
+	}
+}
+
+const outboxDrainInterval = 30 * time.Second
+
+// outboxDrainWorker periodically retries events stashed in the outbox
+// table, deleting each row once it has been successfully republished.
+func (b *EventBus) outboxDrainWorker(ctx context.Context) {
+	defer b.Close()
+	ticker := time.NewTicker(outboxDrainInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			rows, err := b.sch.pgengine.SelectEventOutbox(ctx, 100)
+			if err != nil {
+				b.sch.l.WithError(err).Error("Could not read event outbox")
+				continue
+			}
+			for _, row := range rows {
+				var event Event
+				if err := json.Unmarshal(row.Payload, &event); err != nil {
+					b.sch.l.WithError(err).Error("Could not unmarshal outbox event, dropping")
+					b.sch.pgengine.DeleteEventOutbox(ctx, row.ID)
+					continue
+				}
+				if err := b.sink.Publish(ctx, event); err != nil {
+					continue
+				}
+				b.sch.pgengine.DeleteEventOutbox(ctx, row.ID)
+			}
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSink is an EventSink test double that records published events and
+// can be configured to fail a fixed number of times before succeeding.
+type fakeSink struct {
+	mu        sync.Mutex
+	published []Event
+	failTimes int
+	closed    int
+}
+
+func (s *fakeSink) Publish(ctx context.Context, event Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failTimes > 0 {
+		s.failTimes--
+		return errFakeSinkPublish
+	}
+	s.published = append(s.published, event)
+	return nil
+}
+
+func (s *fakeSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed++
+	return nil
+}
+
+func (s *fakeSink) publishedCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.published)
+}
+
+type fakeSinkPublishError struct{}
+
+func (fakeSinkPublishError) Error() string { return "fake sink: publish failed" }
+
+var errFakeSinkPublish = fakeSinkPublishError{}
+
+// newTestEventBus builds an EventBus wired to sink with an outbox
+// recorder instead of a real pgengine connection, using bufferSize for
+// its channel capacity so tests can force the buffer-full fallback path.
+func newTestEventBus(sink EventSink, bufferSize int) (*EventBus, *outboxRecorder) {
+	rec := &outboxRecorder{}
+	b := &EventBus{
+		sch:          newTestScheduler(),
+		sink:         sink,
+		buffer:       make(chan Event, bufferSize),
+		insertOutbox: rec.insert,
+	}
+	return b, rec
+}
+
+type outboxRecorder struct {
+	mu      sync.Mutex
+	entries [][]byte
+}
+
+func (r *outboxRecorder) insert(ctx context.Context, payload []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, payload)
+	return nil
+}
+
+func (r *outboxRecorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.entries)
+}
+
+func TestEventBusPublishDeliversToSink(t *testing.T) {
+	sink := &fakeSink{}
+	b, rec := newTestEventBus(sink, eventBufferSize)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.Run(ctx)
+
+	b.Publish(Event{Type: EventChainDone, ChainID: 1})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for sink.publishedCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := sink.publishedCount(); got != 1 {
+		t.Fatalf("expected 1 event delivered to sink, got %d", got)
+	}
+	if rec.count() != 0 {
+		t.Fatalf("expected no outbox writes on the happy path, got %d", rec.count())
+	}
+}
+
+func TestEventBusPublishFallsBackToOutboxWhenBufferFull(t *testing.T) {
+	sink := &fakeSink{}
+	// Buffer of 1, and nobody draining it: the first Publish fills the
+	// buffer, the second must overflow straight to the outbox instead of
+	// blocking or dropping the event.
+	b, rec := newTestEventBus(sink, 1)
+
+	b.Publish(Event{Type: EventChainStarted, ChainID: 1})
+	b.Publish(Event{Type: EventChainStarted, ChainID: 2})
+
+	if rec.count() != 1 {
+		t.Fatalf("expected 1 event written to the outbox on buffer overflow, got %d", rec.count())
+	}
+}
+
+func TestEventBusRunFallsBackToOutboxOnSinkError(t *testing.T) {
+	sink := &fakeSink{failTimes: 1}
+	b, rec := newTestEventBus(sink, eventBufferSize)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.Run(ctx)
+
+	b.Publish(Event{Type: EventTaskFinished, ChainID: 1})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for rec.count() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := rec.count(); got != 1 {
+		t.Fatalf("expected the failed publish to fall back to the outbox exactly once, got %d", got)
+	}
+}
+
+func TestEventBusCloseClosesSinkExactlyOnce(t *testing.T) {
+	sink := &fakeSink{}
+	b, _ := newTestEventBus(sink, eventBufferSize)
+
+	if err := b.Close(); err != nil {
+		t.Fatalf("unexpected error from Close: %v", err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatalf("unexpected error from second Close: %v", err)
+	}
+	if sink.closed != 1 {
+		t.Fatalf("expected sink to be closed exactly once, got %d", sink.closed)
+	}
+}
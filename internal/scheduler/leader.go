@@ -0,0 +1,107 @@
+package scheduler
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// defaultLeaseRefresh is how often a LeaderElector re-attempts (as a
+// follower) or renews (as the leader) its Postgres advisory lock when the
+// scheduler isn't configured with its own interval.
+const defaultLeaseRefresh = 5 * time.Second
+
+// LeaderElector lets several Scheduler processes point at the same
+// timetable schema while only one of them actually dequeues chains.
+// Leadership is held via pg_try_advisory_lock on a hash of the client
+// name/namespace: whichever process holds the Postgres session-level lock
+// is the leader, and a dropped connection (crash, network partition)
+// releases the lock automatically so a follower can take over within
+// leaseRefresh.
+type LeaderElector struct {
+	sch          *Scheduler
+	clientName   string
+	leaseRefresh time.Duration
+
+	leader  int32 // atomic bool
+	fencing int64 // atomic fencing token, incremented each time leadership is acquired
+}
+
+// NewLeaderElector builds a LeaderElector for clientName, the namespace
+// that SelectChains/SelectRebootChains already scope their queries to.
+func NewLeaderElector(sch *Scheduler, clientName string, leaseRefresh time.Duration) *LeaderElector {
+	if leaseRefresh <= 0 {
+		leaseRefresh = defaultLeaseRefresh
+	}
+	return &LeaderElector{sch: sch, clientName: clientName, leaseRefresh: leaseRefresh}
+}
+
+// Run attempts to acquire (and then periodically confirm) the advisory
+// lock until ctx is cancelled. It never returns an error: losing or
+// failing to acquire the lock just means IsLeader stays false until the
+// next tick.
+func (le *LeaderElector) Run(ctx context.Context) {
+	ticker := time.NewTicker(le.leaseRefresh)
+	defer ticker.Stop()
+	le.tryAcquire(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			le.release(context.Background())
+			return
+		case <-ticker.C:
+			le.tryAcquire(ctx)
+		}
+	}
+}
+
+func (le *LeaderElector) tryAcquire(ctx context.Context) {
+	acquired, token, err := le.sch.pgengine.TryAcquireLeaderLock(ctx, le.clientName)
+	if err != nil {
+		le.sch.l.WithError(err).Error("Leader election check failed")
+		atomic.StoreInt32(&le.leader, 0)
+		return
+	}
+	wasLeader := atomic.LoadInt32(&le.leader) == 1
+	if acquired {
+		atomic.StoreInt64(&le.fencing, token)
+		atomic.StoreInt32(&le.leader, 1)
+		if !wasLeader {
+			le.sch.l.WithField("client", le.clientName).WithField("fencing_token", token).
+				Info("Acquired scheduler leadership")
+		}
+	} else {
+		atomic.StoreInt32(&le.leader, 0)
+		if wasLeader {
+			le.sch.l.WithField("client", le.clientName).Warn("Lost scheduler leadership")
+		}
+	}
+}
+
+func (le *LeaderElector) release(ctx context.Context) {
+	if atomic.LoadInt32(&le.leader) != 1 {
+		return
+	}
+	if err := le.sch.pgengine.ReleaseLeaderLock(ctx, le.clientName); err != nil {
+		le.sch.l.WithError(err).Error("Could not release leader lock on shutdown")
+	}
+	atomic.StoreInt32(&le.leader, 0)
+}
+
+// IsLeader reports whether this process currently holds the advisory
+// lock. retrieveChainsAndRun and retrieveAsyncChainsAndRun consult this
+// before dequeuing so followers stay hot without double-executing chains.
+func (le *LeaderElector) IsLeader() bool {
+	return le != nil && atomic.LoadInt32(&le.leader) == 1
+}
+
+// FencingToken returns the token written to timetable.leader the last
+// time this process acquired leadership, so writes racing a deposed
+// leader (e.g. an in-flight UpdateChainRunStatus) can be detected and
+// rejected by comparing against the row's current token.
+func (le *LeaderElector) FencingToken() int64 {
+	if le == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&le.fencing)
+}
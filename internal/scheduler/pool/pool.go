@@ -0,0 +1,309 @@
+// Package pool implements a bounded, priority-aware worker pool, modeled
+// loosely on github.com/alitto/pond: a small set of goroutines pull tasks
+// off a heap-ordered queue, scaling between MinWorkers and MaxWorkers
+// based on load, and the pool can be drained gracefully on shutdown.
+package pool
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RejectPolicy controls what happens when Submit is called on a pool
+// whose queue is already at capacity.
+type RejectPolicy int
+
+// Queue-full behaviors available to Options.RejectPolicy.
+const (
+	// RejectBlock blocks Submit until room is available or ctx is done.
+	RejectBlock RejectPolicy = iota
+	// RejectDropOldest evicts the lowest-priority/oldest queued task to
+	// make room for the new one.
+	RejectDropOldest
+	// RejectError makes Submit return ErrQueueFull immediately.
+	RejectError
+)
+
+// Task is a unit of work submitted to the Pool. Higher Priority values run
+// first; ties are broken FIFO by submission order.
+type Task interface {
+	Priority() int
+	Run(ctx context.Context)
+}
+
+// Options configures a Pool.
+type Options struct {
+	MinWorkers   int
+	MaxWorkers   int
+	IdleTimeout  time.Duration
+	QueueSize    int
+	RejectPolicy RejectPolicy
+}
+
+// Pool is a bounded, priority-ordered worker pool.
+type Pool struct {
+	opts Options
+
+	mu          sync.Mutex
+	queue       taskHeap
+	seq         int
+	workers     int
+	idleWorkers []chan struct{} // wake channels of workers currently blocked waiting for work
+	draining    bool
+	wg          sync.WaitGroup
+
+	metrics poolMetrics
+}
+
+type queuedTask struct {
+	task     Task
+	ctx      context.Context
+	priority int
+	seq      int
+	enqueued time.Time
+}
+
+// taskHeap is a max-heap on priority, FIFO among equal priorities.
+type taskHeap []*queuedTask
+
+func (h taskHeap) Len() int { return len(h) }
+func (h taskHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+func (h taskHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *taskHeap) Push(x interface{}) { *h = append(*h, x.(*queuedTask)) }
+func (h *taskHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+type poolMetrics struct {
+	queueSize    prometheus.Gauge
+	activeCount  prometheus.Gauge
+	waitDuration prometheus.Histogram
+}
+
+// ErrQueueFull is returned by Submit when RejectPolicy is RejectError and
+// the queue is at capacity.
+type ErrQueueFull struct{}
+
+func (ErrQueueFull) Error() string { return "pool: queue is full" }
+
+// New creates a Pool and starts MinWorkers goroutines.
+func New(opts Options) *Pool {
+	if opts.MinWorkers <= 0 {
+		opts.MinWorkers = 1
+	}
+	if opts.MaxWorkers < opts.MinWorkers {
+		opts.MaxWorkers = opts.MinWorkers
+	}
+	p := &Pool{
+		opts: opts,
+		metrics: poolMetrics{
+			queueSize: prometheus.NewGauge(prometheus.GaugeOpts{
+				Namespace: "pg_timetable", Subsystem: "pool", Name: "queue_size",
+				Help: "Number of chains waiting in the worker pool queue.",
+			}),
+			activeCount: prometheus.NewGauge(prometheus.GaugeOpts{
+				Namespace: "pg_timetable", Subsystem: "pool", Name: "active_workers",
+				Help: "Number of worker goroutines currently executing a task.",
+			}),
+			waitDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+				Namespace: "pg_timetable", Subsystem: "pool", Name: "queue_wait_seconds",
+				Help: "Time a task spent queued before a worker picked it up.",
+			}),
+		},
+	}
+	for i := 0; i < opts.MinWorkers; i++ {
+		p.spawnWorker()
+	}
+	return p
+}
+
+// QueueLen returns the number of tasks currently waiting in the queue
+// (i.e. not yet picked up by a worker).
+func (p *Pool) QueueLen() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.queue)
+}
+
+// Collectors returns the Prometheus collectors exposed by the pool, for
+// registration against the process registry alongside the existing
+// logger-based metrics.
+func (p *Pool) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{p.metrics.queueSize, p.metrics.activeCount, p.metrics.waitDuration}
+}
+
+// Submit enqueues task, honoring the pool's RejectPolicy if the queue is
+// already at QueueSize capacity.
+func (p *Pool) Submit(ctx context.Context, task Task) error {
+	p.mu.Lock()
+	if p.draining {
+		p.mu.Unlock()
+		return ErrQueueFull{}
+	}
+	for p.opts.QueueSize > 0 && len(p.queue) >= p.opts.QueueSize {
+		switch p.opts.RejectPolicy {
+		case RejectDropOldest:
+			worst := 0
+			for i, qt := range p.queue {
+				w := p.queue[worst]
+				if qt.priority < w.priority || (qt.priority == w.priority && qt.seq < w.seq) {
+					worst = i
+				}
+			}
+			heap.Remove(&p.queue, worst)
+		case RejectError:
+			p.mu.Unlock()
+			return ErrQueueFull{}
+		default: // RejectBlock
+			p.mu.Unlock()
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(10 * time.Millisecond):
+			}
+			p.mu.Lock()
+			continue
+		}
+	}
+	p.seq++
+	heap.Push(&p.queue, &queuedTask{task: task, ctx: ctx, priority: task.Priority(), seq: p.seq, enqueued: time.Now()})
+	p.metrics.queueSize.Set(float64(len(p.queue)))
+	p.wakeOne()
+	if len(p.idleWorkers) == 0 && p.workers < p.opts.MaxWorkers && p.workers < len(p.queue) {
+		p.spawnWorker()
+	}
+	p.mu.Unlock()
+	return nil
+}
+
+// wakeOne wakes exactly one currently-idle worker, if any are waiting.
+// Must be called with p.mu held. Each worker owns its wake channel for as
+// long as (and only as long as) it's registered in idleWorkers, so this
+// can never signal a worker that has already moved on — unlike
+// sync.Cond.Signal, which can wake a disposable per-spin waiter goroutine
+// that's no longer attached to any real worker.
+func (p *Pool) wakeOne() {
+	if len(p.idleWorkers) == 0 {
+		return
+	}
+	n := len(p.idleWorkers) - 1
+	wake := p.idleWorkers[n]
+	p.idleWorkers = p.idleWorkers[:n]
+	wake <- struct{}{}
+}
+
+// spawnWorker must be called with p.mu held.
+func (p *Pool) spawnWorker() {
+	p.workers++
+	p.wg.Add(1)
+	go p.workerLoop()
+}
+
+// workerLoop is the body of every pool goroutine. It owns exactly one
+// wake channel for its entire lifetime (recreated each time it goes idle)
+// and only ever registers *itself* in idleWorkers, so a signal meant for
+// "some idle worker" always reaches a worker that's actually still
+// there to receive it.
+func (p *Pool) workerLoop() {
+	defer p.wg.Done()
+	idle := p.opts.IdleTimeout
+	if idle <= 0 {
+		idle = time.Minute
+	}
+	for {
+		p.mu.Lock()
+		if len(p.queue) > 0 {
+			item := heap.Pop(&p.queue).(*queuedTask)
+			p.metrics.queueSize.Set(float64(len(p.queue)))
+			p.metrics.waitDuration.Observe(time.Since(item.enqueued).Seconds())
+			p.metrics.activeCount.Inc()
+			p.mu.Unlock()
+
+			item.task.Run(item.ctx)
+
+			p.mu.Lock()
+			p.metrics.activeCount.Dec()
+			p.mu.Unlock()
+			continue
+		}
+		if p.draining {
+			p.workers--
+			p.mu.Unlock()
+			return
+		}
+		wake := make(chan struct{}, 1)
+		p.idleWorkers = append(p.idleWorkers, wake)
+		p.mu.Unlock()
+
+		select {
+		case <-wake:
+			// Either handed a task directly, or woken for draining; loop
+			// back around to re-check the queue/draining flag.
+		case <-time.After(idle):
+			p.mu.Lock()
+			if !p.removeIdle(wake) {
+				// Already popped by wakeOne between the timer firing and
+				// us acquiring the lock; a wake-up is in flight for us,
+				// so don't exit — go collect it next iteration.
+				p.mu.Unlock()
+				continue
+			}
+			if p.workers > p.opts.MinWorkers {
+				p.workers--
+				p.mu.Unlock()
+				return
+			}
+			p.mu.Unlock()
+		}
+	}
+}
+
+// removeIdle deletes wake from idleWorkers if it's still present, and
+// reports whether it found (and removed) it. Must be called with p.mu held.
+func (p *Pool) removeIdle(wake chan struct{}) bool {
+	for i, w := range p.idleWorkers {
+		if w == wake {
+			p.idleWorkers = append(p.idleWorkers[:i], p.idleWorkers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Drain stops accepting new tasks (further Submit calls return
+// ErrQueueFull) and waits for in-flight and already-queued tasks to
+// finish, or for ctx to expire, whichever comes first.
+func (p *Pool) Drain(ctx context.Context) error {
+	p.mu.Lock()
+	p.draining = true
+	for _, wake := range p.idleWorkers {
+		wake <- struct{}{}
+	}
+	p.idleWorkers = nil
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
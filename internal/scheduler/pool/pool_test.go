@@ -0,0 +1,110 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type countingTask struct {
+	priority int
+	done     func()
+}
+
+func (t countingTask) Priority() int { return t.priority }
+func (t countingTask) Run(ctx context.Context) {
+	t.done()
+}
+
+// TestPoolConcurrentSubmit submits far more tasks than MaxWorkers from many
+// goroutines at once and checks every one of them actually runs exactly
+// once. This is the scenario the old sync.Cond-based workerLoop could drop:
+// a worker could time out and exit while a stray per-spin waiter goroutine
+// was left holding a signal meant for a real worker, leaving a queued task
+// stuck until IdleTimeout.
+func TestPoolConcurrentSubmit(t *testing.T) {
+	p := New(Options{MinWorkers: 2, MaxWorkers: 8, IdleTimeout: 50 * time.Millisecond, QueueSize: 0})
+
+	const numTasks = 500
+	var ran int64
+	var wg sync.WaitGroup
+	wg.Add(numTasks)
+
+	var submitWG sync.WaitGroup
+	for i := 0; i < numTasks; i++ {
+		submitWG.Add(1)
+		go func(i int) {
+			defer submitWG.Done()
+			err := p.Submit(context.Background(), countingTask{
+				priority: i % 3,
+				done: func() {
+					atomic.AddInt64(&ran, 1)
+					wg.Done()
+				},
+			})
+			if err != nil {
+				t.Errorf("unexpected Submit error: %v", err)
+			}
+		}(i)
+	}
+	submitWG.Wait()
+
+	doneCh := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(doneCh)
+	}()
+	select {
+	case <-doneCh:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("timed out waiting for all tasks to run; ran=%d/%d", atomic.LoadInt64(&ran), numTasks)
+	}
+	if got := atomic.LoadInt64(&ran); got != numTasks {
+		t.Fatalf("expected %d tasks to run, got %d", numTasks, got)
+	}
+
+	if err := p.Drain(context.Background()); err != nil {
+		t.Fatalf("unexpected error draining pool: %v", err)
+	}
+}
+
+// TestPoolIdleWorkersScaleDown checks that workers spawned above MinWorkers
+// to handle a burst eventually exit once the queue drains and they've sat
+// idle past IdleTimeout, rather than leaking goroutines forever.
+func TestPoolIdleWorkersScaleDown(t *testing.T) {
+	p := New(Options{MinWorkers: 1, MaxWorkers: 4, IdleTimeout: 20 * time.Millisecond, QueueSize: 0})
+
+	var wg sync.WaitGroup
+	wg.Add(4)
+	block := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		if err := p.Submit(context.Background(), countingTask{done: func() {
+			<-block
+			wg.Done()
+		}}); err != nil {
+			t.Fatalf("unexpected Submit error: %v", err)
+		}
+	}
+	close(block)
+	wg.Wait()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		p.mu.Lock()
+		workers := p.workers
+		p.mu.Unlock()
+		if workers <= p.opts.MinWorkers {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("workers did not scale back down to MinWorkers; still have %d", workers)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := p.Drain(context.Background()); err != nil {
+		t.Fatalf("unexpected error draining pool: %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package scheduler
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// defaultRetryMaxAttempts is used when a chain does not configure its own
+// RetryMaxAttempts (zero means "use the default", not "never retry").
+const defaultRetryMaxAttempts = 3
+
+// defaultRetryBackoffBase and defaultRetryBackoffMax bound the exponential
+// backoff applied between retry attempts when a chain leaves the
+// retry_backoff_* columns unset.
+const (
+	defaultRetryBackoffBase = 2 * time.Second
+	defaultRetryBackoffMax  = 5 * time.Minute
+)
+
+// retryJob describes a chain that failed and is waiting to be resubmitted
+// to the worker pool once its backoff window has elapsed.
+type retryJob struct {
+	chain     Chain
+	attempt   int
+	notBefore time.Time
+}
+
+// shouldRetry decides whether a failed chain execution is eligible for a
+// retry, based on the exit code allow/deny lists configured on the chain.
+// An exit code present in RetryExcludeCodes is never retried, even if it
+// also appears in RetryOnExitCodes. An empty RetryOnExitCodes means "retry
+// on any exit code that isn't excluded".
+func (chain Chain) shouldRetry(attempt int, retCode int) bool {
+	maxAttempts := chain.RetryMaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = defaultRetryMaxAttempts
+	}
+	if attempt >= maxAttempts {
+		return false
+	}
+	for _, code := range chain.RetryExcludeCodes {
+		if code == retCode {
+			return false
+		}
+	}
+	if len(chain.RetryOnExitCodes) == 0 {
+		return true
+	}
+	for _, code := range chain.RetryOnExitCodes {
+		if code == retCode {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff computes the exponential delay before the next retry attempt,
+// with full jitter applied so that many simultaneously failing chains
+// don't thunder back onto the worker pool at the same instant.
+func (chain Chain) backoff(attempt int) time.Duration {
+	base := time.Duration(chain.RetryBackoffBase) * time.Millisecond
+	if base <= 0 {
+		base = defaultRetryBackoffBase
+	}
+	maxDelay := time.Duration(chain.RetryBackoffMax) * time.Millisecond
+	if maxDelay <= 0 {
+		maxDelay = defaultRetryBackoffMax
+	}
+	delay := base << uint(attempt-1)
+	if delay <= 0 || delay > maxDelay { // guard against overflow on large attempt counts
+		delay = maxDelay
+	}
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+// handleChainFailure is called after a chain's transaction has been rolled
+// back. It either schedules a retry (requeued onto retryChan and, once its
+// backoff elapses, into the worker pool) or, once retries are exhausted, pauses
+// the chain so SelectChains stops picking it up until an operator resumes
+// it explicitly.
+func (sch *Scheduler) handleChainFailure(ctx context.Context, chainID int, taskID int, retCode int, attempt int) {
+	var chain Chain
+	if err := sch.pgengine.SelectChain(ctx, &chain, taskID); err != nil {
+		sch.l.WithError(err).Error("Could not reload chain to evaluate retry policy")
+		return
+	}
+	if !chain.shouldRetry(attempt, retCode) {
+		sch.l.WithField("chain", chainID).WithField("attempts", attempt).
+			Error("Retries exhausted, pausing chain")
+		sch.pgengine.PauseChain(ctx, chainID, "retries exhausted")
+		return
+	}
+	delay := chain.backoff(attempt)
+	chain.attempt = attempt + 1
+	sch.l.WithField("chain", chainID).WithField("attempt", chain.attempt).WithField("delay", delay).
+		Info("Scheduling chain retry")
+	sch.retryChan <- retryJob{chain: chain, attempt: chain.attempt, notBefore: time.Now().Add(delay)}
+}
+
+// retryChainWorker dispatches each retryJob to its own timer goroutine as
+// soon as it arrives, instead of waiting out one job's backoff before even
+// looking at the next: a chain queued with a long backoff must not stall
+// every other chain's much shorter retry behind it.
+func (sch *Scheduler) retryChainWorker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-sch.retryChan:
+			go sch.waitAndResubmit(ctx, job)
+		}
+	}
+}
+
+// waitAndResubmit sleeps out a single retryJob's backoff window and then
+// resubmits the chain to the worker pool. Each job gets its own timer, so
+// jobs resolve independently of however long their siblings still have to
+// wait.
+func (sch *Scheduler) waitAndResubmit(ctx context.Context, job retryJob) {
+	if wait := time.Until(job.notBefore); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+	}
+	sch.submitChain(ctx, job.chain)
+}
+
+// ResumeChain clears the paused state for a chain so it becomes eligible
+// for SelectChains again. Nothing in this package calls it, and the
+// diagnostics HTTP server has no resume endpoint (only cancel and trigger).
+func (sch *Scheduler) ResumeChain(ctx context.Context, chainID int) error {
+	return sch.pgengine.ResumeChain(ctx, chainID)
+}
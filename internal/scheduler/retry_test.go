@@ -0,0 +1,87 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChainShouldRetry(t *testing.T) {
+	tests := []struct {
+		name    string
+		chain   Chain
+		attempt int
+		retCode int
+		want    bool
+	}{
+		{
+			name:    "default max attempts, any exit code",
+			chain:   Chain{},
+			attempt: 1,
+			retCode: 1,
+			want:    true,
+		},
+		{
+			name:    "default max attempts exhausted",
+			chain:   Chain{},
+			attempt: defaultRetryMaxAttempts,
+			retCode: 1,
+			want:    false,
+		},
+		{
+			name:    "custom max attempts exhausted",
+			chain:   Chain{RetryMaxAttempts: 1},
+			attempt: 1,
+			retCode: 1,
+			want:    false,
+		},
+		{
+			name:    "exclude list always wins over allow list",
+			chain:   Chain{RetryOnExitCodes: []int{1, 2}, RetryExcludeCodes: []int{1}},
+			attempt: 1,
+			retCode: 1,
+			want:    false,
+		},
+		{
+			name:    "allow list restricts retry to listed codes",
+			chain:   Chain{RetryOnExitCodes: []int{2}},
+			attempt: 1,
+			retCode: 1,
+			want:    false,
+		},
+		{
+			name:    "allow list permits a listed code",
+			chain:   Chain{RetryOnExitCodes: []int{1}},
+			attempt: 1,
+			retCode: 1,
+			want:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.chain.shouldRetry(tt.attempt, tt.retCode); got != tt.want {
+				t.Errorf("shouldRetry(%d, %d) = %v, want %v", tt.attempt, tt.retCode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChainBackoffBounds(t *testing.T) {
+	chain := Chain{RetryBackoffBase: 100, RetryBackoffMax: 1000}
+	for attempt := 1; attempt <= 10; attempt++ {
+		delay := chain.backoff(attempt)
+		if delay < 0 {
+			t.Fatalf("attempt %d: backoff returned a negative delay: %v", attempt, delay)
+		}
+		if delay > time.Duration(chain.RetryBackoffMax)*time.Millisecond {
+			t.Fatalf("attempt %d: backoff %v exceeded RetryBackoffMax %dms", attempt, delay, chain.RetryBackoffMax)
+		}
+	}
+}
+
+func TestChainBackoffUsesDefaultsWhenUnset(t *testing.T) {
+	chain := Chain{}
+	delay := chain.backoff(1)
+	if delay > defaultRetryBackoffMax {
+		t.Fatalf("backoff %v exceeded defaultRetryBackoffMax %v", delay, defaultRetryBackoffMax)
+	}
+}
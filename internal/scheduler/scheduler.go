@@ -0,0 +1,135 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cybertec-postgresql/pg_timetable/internal/log"
+	"github.com/cybertec-postgresql/pg_timetable/internal/pgengine"
+	"github.com/cybertec-postgresql/pg_timetable/internal/scheduler/pool"
+)
+
+// refetchTimeout is how often (in seconds) retrieveChainsAndRun polls for
+// newly-due chains, and the unit retrieveChainsAndRun's spread-out-startup
+// logic uses to avoid bursting many chains onto the pool at once.
+const refetchTimeout = 2
+
+// ResourceConfig bounds how much of the host the scheduler is allowed to
+// use; CronWorkers sizes both the legacy refetch spread in
+// retrieveChainsAndRun and the default worker pool built by newChainPool.
+type ResourceConfig struct {
+	CronWorkers int
+}
+
+// Config is the subset of scheduler-relevant settings normally parsed
+// from the command line/config file and handed to NewScheduler.
+type Config struct {
+	Resource ResourceConfig
+
+	// ClientName namespaces the advisory lock taken by LeaderElector, so
+	// multiple independently-configured schedulers can share one
+	// Postgres instance without contending for the same lock.
+	ClientName string
+	// LeaseRefresh is how often LeaderElector re-confirms leadership.
+	LeaseRefresh time.Duration
+	// DiagListen is the --diag-listen address; empty disables the
+	// diagnostics HTTP server entirely.
+	DiagListen string
+	// EventSink is the --event-sink URL; empty disables event publishing.
+	EventSink string
+}
+
+// activeChain tracks one currently-executing chain instance: the cancel
+// func that stops it (used by the diagnostics cancel endpoint and the
+// STOP chain signal) and when it started (reported by the diagnostics
+// live endpoint).
+type activeChain struct {
+	cancel    context.CancelFunc
+	startedAt time.Time
+}
+
+// Scheduler owns the full lifecycle of chain execution: dequeuing due
+// chains, running them through the worker pool, retrying/pausing on
+// failure, and reporting state through the diagnostics endpoint.
+type Scheduler struct {
+	pgengine *pgengine.PgEngine
+	l        log.LoggerIface
+	cfg      *Config
+
+	exclusiveMutex sync.RWMutex
+
+	activeChainMutex sync.Mutex
+	activeChains     map[int]activeChain
+
+	pool      *pool.Pool
+	leader    *LeaderElector
+	events    *EventBus
+	retryChan chan retryJob
+}
+
+// NewScheduler wires up a Scheduler ready to Run. The worker pool starts
+// its MinWorkers goroutines here; the leader elector and event bus are
+// constructed here but not started until Run is called.
+func NewScheduler(pge *pgengine.PgEngine, cfg *Config, l log.LoggerIface) *Scheduler {
+	sch := &Scheduler{
+		pgengine:     pge,
+		l:            l,
+		cfg:          cfg,
+		activeChains: make(map[int]activeChain),
+		retryChan:    make(chan retryJob, 64),
+	}
+	sch.pool = newChainPool(sch)
+	sch.leader = NewLeaderElector(sch, cfg.ClientName, cfg.LeaseRefresh)
+	sink, err := NewEventSink(cfg.EventSink)
+	if err != nil {
+		l.WithError(err).Error("Could not build event sink, lifecycle events will not be published")
+	}
+	sch.events = NewEventBus(sch, sink)
+	return sch
+}
+
+// Config returns the settings this Scheduler was constructed with.
+func (sch *Scheduler) Config() *Config {
+	return sch.cfg
+}
+
+// Run starts every background subsystem (leader election, the retry
+// worker, the event bus and its outbox drain, the optional diagnostics
+// server) and then loops dequeuing due chains until ctx is cancelled. It
+// blocks until ctx is done and then until in-flight chains have been
+// drained or a 30-second drain timeout expires, whichever comes first.
+func (sch *Scheduler) Run(ctx context.Context) error {
+	go sch.leader.Run(ctx)
+	go sch.retryChainWorker(ctx)
+	if sch.events.sink != nil {
+		go sch.events.Run(ctx)
+		go sch.events.outboxDrainWorker(ctx)
+	}
+
+	var diag *DiagnosticsServer
+	if sch.cfg.DiagListen != "" {
+		diag = NewDiagnosticsServer(sch, sch.cfg.DiagListen)
+		go func() {
+			if err := diag.Start(ctx); err != nil {
+				sch.l.WithError(err).Error("Diagnostics server stopped unexpectedly")
+			}
+		}()
+	}
+
+	sch.retrieveChainsAndRun(ctx, true) // pick up @reboot chains once on startup
+
+	ticker := time.NewTicker(refetchTimeout * time.Second)
+	defer ticker.Stop()
+	go sch.retrieveAsyncChainsAndRun(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			drainCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			return sch.pool.Drain(drainCtx)
+		case <-ticker.C:
+			sch.retrieveChainsAndRun(ctx, false)
+		}
+	}
+}
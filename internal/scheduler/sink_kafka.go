@@ -0,0 +1,48 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	kafka "github.com/segmentio/kafka-go"
+)
+
+// kafkaSink publishes Events to a Kafka topic. The URL host (a
+// comma-separated list) is used as the broker list and the path as the
+// topic, e.g. kafka://broker1:9092,broker2:9092/pg_timetable.events.
+type kafkaSink struct {
+	writer *kafka.Writer
+}
+
+func newKafkaSink(u *url.URL) (EventSink, error) {
+	topic := strings.TrimPrefix(u.Path, "/")
+	if topic == "" {
+		topic = "pg_timetable.events"
+	}
+	brokers := strings.Split(u.Host, ",")
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.LeastBytes{},
+		RequiredAcks: kafka.RequireAll,
+	}
+	return &kafkaSink{writer: writer}, nil
+}
+
+// Publish marshals event as JSON and writes it to the configured topic.
+// RequireAll acks mean a successful return implies the message is
+// durable; any error is treated as a delivery failure by EventBus and
+// falls back to the outbox table.
+func (s *kafkaSink) Publish(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return s.writer.WriteMessages(ctx, kafka.Message{Value: payload})
+}
+
+func (s *kafkaSink) Close() error {
+	return s.writer.Close()
+}
@@ -0,0 +1,53 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+)
+
+// natsSink publishes Events to a NATS JetStream stream. The URL path
+// (minus the leading slash) is used as the publish subject.
+type natsSink struct {
+	conn    *nats.Conn
+	js      nats.JetStreamContext
+	subject string
+}
+
+func newNATSSink(u *url.URL) (EventSink, error) {
+	subject := strings.TrimPrefix(u.Path, "/")
+	if subject == "" {
+		subject = "pg_timetable.events"
+	}
+	conn, err := nats.Connect(u.Host)
+	if err != nil {
+		return nil, err
+	}
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &natsSink{conn: conn, js: js, subject: subject}, nil
+}
+
+// Publish marshals event as JSON and publishes it to the configured
+// JetStream subject, relying on JetStream's ack to give at-least-once
+// delivery: the outbox fallback in EventBus only kicks in if this call
+// itself returns an error.
+func (s *natsSink) Publish(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = s.js.Publish(s.subject, payload)
+	return err
+}
+
+func (s *natsSink) Close() error {
+	s.conn.Close()
+	return nil
+}